@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/haningnanda/svelte-commerce/email-verifier/verifier"
+)
+
+// checkDeliverability runs the MX/RCPT pre-check against email and reports
+// whether the send should be rejected outright, so obviously-invalid
+// addresses don't burn sender reputation or a paid provider's quota.
+func checkDeliverability(email string) (reject bool, reason string) {
+	if !envBool("EMAIL_DELIVERABILITY_CHECK", true) {
+		return false, ""
+	}
+
+	result := verifier.Check(email, verifier.Options{
+		FromEmail:       os.Getenv("MAIL_FROM"),
+		EnableSMTPProbe: envBool("EMAIL_DELIVERABILITY_SMTP_PROBE", false),
+		DialTimeout:     5 * time.Second,
+	})
+
+	switch {
+	case !result.Syntax:
+		return true, "invalid email syntax"
+	case result.Reachability == verifier.ReachabilityNo:
+		return true, "domain has no deliverable mail server"
+	case result.Disposable && envBool("EMAIL_REJECT_DISPOSABLE", false):
+		return true, "disposable email domains are not allowed"
+	default:
+		return false, ""
+	}
+}
+
+func envBool(key string, fallback bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mailer sends a transactional email as a multipart/alternative (plaintext +
+// HTML) message and reports back the provider's message ID (if any) so
+// delivery can be traced in logs.
+type Mailer interface {
+	Send(to, subject, textBody, htmlBody string) (messageID string, err error)
+}
+
+// newMailer selects a Mailer implementation based on MAIL_BACKEND
+// (smtp|mailgun|postal|ses), defaulting to smtp for backwards compatibility.
+func newMailer() (Mailer, error) {
+	backend := os.Getenv("MAIL_BACKEND")
+	if backend == "" {
+		backend = "smtp"
+	}
+
+	switch backend {
+	case "smtp":
+		return newSMTPMailer()
+	case "mailgun":
+		return newMailgunMailer()
+	case "postal":
+		return newPostalMailer()
+	case "ses":
+		return newSESMailer()
+	default:
+		return nil, fmt.Errorf("unsupported MAIL_BACKEND %q", backend)
+	}
+}
+
+// smtpMailer is the original delivery path: a direct SMTP relay.
+type smtpMailer struct {
+	host     string
+	port     string
+	user     string
+	password string
+}
+
+func newSMTPMailer() (*smtpMailer, error) {
+	m := &smtpMailer{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     os.Getenv("SMTP_PORT"),
+		user:     os.Getenv("SMTP_USER"),
+		password: os.Getenv("SMTP_PASSWORD"),
+	}
+	if m.host == "" || m.port == "" || m.user == "" || m.password == "" {
+		return nil, fmt.Errorf("SMTP environment variables are not set")
+	}
+	return m, nil
+}
+
+func (m *smtpMailer) Send(to, subject, textBody, htmlBody string) (string, error) {
+	from := mail.Address{Name: brandName(), Address: m.user}
+	msg, err := buildMIMEMessage(from, mail.Address{Address: to}, subject, textBody, htmlBody)
+	if err != nil {
+		return "", err
+	}
+
+	auth := smtp.PlainAuth("", m.user, m.password, m.host)
+	addr := fmt.Sprintf("%s:%s", m.host, m.port)
+	if err := smtp.SendMail(addr, auth, m.user, []string{to}, msg); err != nil {
+		return "", err
+	}
+	// Plain SMTP has no concept of a provider message ID.
+	return "", nil
+}
+
+// mailgunMailer delivers over Mailgun's HTTP API, which works on
+// PaaS environments (e.g. App Engine) where outbound SMTP is blocked.
+type mailgunMailer struct {
+	apiKey string
+	domain string
+	from   string
+}
+
+func newMailgunMailer() (*mailgunMailer, error) {
+	m := &mailgunMailer{
+		apiKey: os.Getenv("MAILGUN_API_KEY"),
+		domain: os.Getenv("MAILGUN_DOMAIN"),
+		from:   os.Getenv("MAIL_FROM"),
+	}
+	if m.apiKey == "" || m.domain == "" {
+		return nil, fmt.Errorf("MAILGUN_API_KEY and MAILGUN_DOMAIN must be set")
+	}
+	if m.from == "" {
+		m.from = fmt.Sprintf("%s <noreply@%s>", brandName(), m.domain)
+	}
+	return m, nil
+}
+
+func (m *mailgunMailer) Send(to, subject, textBody, htmlBody string) (string, error) {
+	endpoint := fmt.Sprintf("https://api.mailgun.net/v3/%s/messages", m.domain)
+
+	form := url.Values{}
+	form.Set("from", m.from)
+	form.Set("to", to)
+	form.Set("subject", subject)
+	form.Set("text", textBody)
+	form.Set("html", htmlBody)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", m.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("mailgun request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("mailgun response decode failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("mailgun returned %d: %s", resp.StatusCode, result.Message)
+	}
+
+	return result.ID, nil
+}
+
+// postalMailer delivers over a self-hosted Postal server's HTTP API.
+type postalMailer struct {
+	apiBase string
+	apiKey  string
+	from    string
+}
+
+func newPostalMailer() (*postalMailer, error) {
+	m := &postalMailer{
+		apiBase: os.Getenv("POSTAL_API"),
+		apiKey:  os.Getenv("POSTAL_KEY"),
+		from:    os.Getenv("MAIL_FROM"),
+	}
+	if m.apiBase == "" || m.apiKey == "" {
+		return nil, fmt.Errorf("POSTAL_API and POSTAL_KEY must be set")
+	}
+	if m.from == "" {
+		return nil, fmt.Errorf("MAIL_FROM must be set for the postal backend")
+	}
+	return m, nil
+}
+
+func (m *postalMailer) Send(to, subject, textBody, htmlBody string) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/send/message", m.apiBase)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"to":         []string{to},
+		"from":       m.from,
+		"subject":    subject,
+		"plain_body": textBody,
+		"html_body":  htmlBody,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Server-API-Key", m.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("postal request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			MessageID string `json:"message_id"`
+		} `json:"data"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("postal response decode failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("postal returned %d: %s", resp.StatusCode, result.Message)
+	}
+
+	return result.Data.MessageID, nil
+}
+
+// sesMailer delivers through Amazon SES's SendEmail Query API, signed with
+// AWS Signature Version 4. A single signed POST doesn't warrant pulling in
+// the full AWS SDK.
+type sesMailer struct {
+	accessKeyID string
+	secretKey   string
+	region      string
+	from        string
+}
+
+func newSESMailer() (*sesMailer, error) {
+	m := &sesMailer{
+		accessKeyID: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		region:      os.Getenv("AWS_REGION"),
+		from:        os.Getenv("MAIL_FROM"),
+	}
+	if m.accessKeyID == "" || m.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	if m.region == "" {
+		m.region = "us-east-1"
+	}
+	if m.from == "" {
+		return nil, fmt.Errorf("MAIL_FROM must be set for the ses backend")
+	}
+	return m, nil
+}
+
+func (m *sesMailer) Send(to, subject, textBody, htmlBody string) (string, error) {
+	endpoint := fmt.Sprintf("https://email.%s.amazonaws.com/", m.region)
+
+	form := url.Values{}
+	form.Set("Action", "SendEmail")
+	form.Set("Source", m.from)
+	form.Set("Destination.ToAddresses.member.1", to)
+	form.Set("Message.Subject.Data", subject)
+	form.Set("Message.Body.Text.Data", textBody)
+	form.Set("Message.Body.Html.Data", htmlBody)
+	encodedForm := form.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(encodedForm))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	if err := signSESRequest(req, encodedForm, m.accessKeyID, m.secretKey, m.region); err != nil {
+		return "", fmt.Errorf("failed to sign ses request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ses request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("ses response read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ses returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return parseSESMessageID(respBody), nil
+}
+
+// signSESRequest signs req in place using AWS Signature Version 4 for the
+// "ses" service, following the canonical-request/string-to-sign/signing-key
+// recipe from AWS's SigV4 documentation.
+func signSESRequest(req *http.Request, body, accessKeyID, secretKey, region string) error {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, amzDate)
+	signedHeaders := "content-type;host;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"", // no query string params - they're in the signed body
+		canonicalHeaders,
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/ses/aws4_request", dateStamp, region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	signingKey := sesSigningKey(secretKey, dateStamp, region)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, credentialScope, signedHeaders, signature,
+	))
+	return nil
+}
+
+func sesSigningKey(secretKey, dateStamp, region string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretKey), dateStamp)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, "ses")
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseSESMessageID pulls the MessageId out of SES's XML SendEmailResponse,
+// returning "" if the body doesn't parse as expected.
+func parseSESMessageID(body []byte) string {
+	var parsed struct {
+		XMLName xml.Name `xml:"SendEmailResponse"`
+		Result  struct {
+			MessageID string `xml:"MessageId"`
+		} `xml:"SendEmailResult"`
+	}
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return ""
+	}
+	return parsed.Result.MessageID
+}
+
+// sendEmail renders the verification email template and dispatches it
+// through whichever Mailer MAIL_BACKEND selects.
+func sendEmail(to string, verificationLink string) error {
+	mailer, err := newMailer()
+	if err != nil {
+		return err
+	}
+
+	data := EmailData{
+		VerificationLink: verificationLink,
+		BrandName:        brandName(),
+		ExpiresIn:        verificationTTL().String(),
+		SupportEmail:     supportEmail(),
+	}
+
+	htmlBody, textBody, err := renderEmail(templateVerification, data)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s Email Verification", data.BrandName)
+	messageID, err := mailer.Send(to, subject, textBody, htmlBody)
+	if err != nil {
+		return err
+	}
+	if messageID != "" {
+		fmt.Printf("Dispatched verification email to %s (provider message id: %s)\n", to, messageID)
+	}
+	return nil
+}
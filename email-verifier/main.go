@@ -3,20 +3,32 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"net/smtp"
-	"os"
-	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
 )
 
-const verifiedEmailsFile = "verified_emails.txt"
+// store is the verification state backend shared by all handlers.
+var store VerificationStore
 
 func main() {
+	var err error
+	store, err = newVerificationStore()
+	if err != nil {
+		log.Fatalf("Failed to initialize verification store: %v\n", err)
+	}
+	defer store.Close()
+
+	if err := loadEmailTemplates(); err != nil {
+		log.Fatalf("Failed to load email templates: %v\n", err)
+	}
+
+	migrateLegacyVerifiedEmails(store)
+	warnIfAllowAll()
+
 	http.HandleFunc("/send-verification", corsMiddleware(sendVerificationHandler))
+	http.HandleFunc("/user/email/send-verification-email", corsMiddleware(sendVerificationHandler))
 	http.HandleFunc("/verify", corsMiddleware(verifyEmailHandler))
 	http.HandleFunc("/check-verification", corsMiddleware(checkVerificationHandler))
 
@@ -67,16 +79,65 @@ func sendVerificationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rateLimit, err := checkSendRateLimit(store, clientIPFromRequest(r), email)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to check rate limit", http.StatusInternalServerError)
+		return
+	}
+	if rateLimit.limited {
+		writeRateLimitError(w, rateLimit.retryAfter)
+		return
+	}
+
+	if reject, reason := checkDeliverability(email); reject {
+		log.Printf("Rejecting %s: %s\n", email, reason)
+		writeJSONError(w, http.StatusBadRequest, "invalid-email", "Email address does not appear deliverable")
+		return
+	}
+
 	// Check if the email is already verified
 	if isEmailVerified(email) {
-		http.Error(w, "Email is already verified", http.StatusConflict)
+		writeJSONError(w, http.StatusConflict, "email-already-verified", "Email is already verified")
+		return
+	}
+
+	switch verificationMode() {
+	case modeWhitelist:
+		whitelisted, err := isWhitelisted(email)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to check account whitelist", http.StatusInternalServerError)
+			return
+		}
+		if !whitelisted {
+			writeJSONError(w, http.StatusForbidden, "not-whitelisted", "Email is not on the account whitelist")
+			return
+		}
+	case modeAllowAll:
+		if err := store.MarkVerified(email); err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		log.Printf("Auto-verified %v (ACCOUNT_VERIFICATION_MODE=AllowAll)\n", email)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Email auto-verified",
+		})
 		return
 	}
 
-	// Send a verification email using SMTP
-	verificationLink := fmt.Sprintf("http://localhost:8080/verify?email=%s", email)
-	err := sendEmail(email, verificationLink)
+	token, err := generateVerificationToken(store, email)
 	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to generate verification token", http.StatusInternalServerError)
+		return
+	}
+
+	// Send a verification email through the backend MAIL_BACKEND selects
+	verificationLink := fmt.Sprintf("http://localhost:8080/verify?token=%s", token)
+	if err := sendEmail(email, verificationLink); err != nil {
 		log.Println(err)
 		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
 		return
@@ -96,20 +157,27 @@ func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	email := r.URL.Query().Get("email")
-	if email == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid-token", "Token is required")
 		return
 	}
 
-	// Check if the email is already verified
-	if isEmailVerified(email) {
-		http.Error(w, "Email is already verified", http.StatusConflict)
+	email, tokErr := parseVerificationToken(store, token)
+	if tokErr != nil {
+		status := http.StatusBadRequest
+		if tokErr.code == "token-expired" {
+			status = http.StatusGone
+		}
+		writeJSONError(w, status, tokErr.code, tokErr.Error())
 		return
 	}
 
-	// Append the email to the verified emails file
-	if err := appendToFile(verifiedEmailsFile, email+"\n"); err != nil {
+	// ConsumeToken above already cleared this token's pending state, so by
+	// the time we get here the email can't already be verified through
+	// this same token - a second click on the link dies earlier with
+	// invalid-token instead of reaching this handler.
+	if err := store.MarkVerified(email); err != nil {
 		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
 		return
 	}
@@ -144,58 +212,10 @@ func checkVerificationHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func isEmailVerified(email string) bool {
-	file, err := os.Open(verifiedEmailsFile)
+	verified, err := store.IsVerified(email)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false
-		}
-		fmt.Printf("Error reading file: %v\n", err)
+		log.Printf("Error checking verification state for %s: %v\n", email, err)
 		return false
 	}
-	defer file.Close()
-
-	content, err := ioutil.ReadAll(file)
-	if err != nil {
-		fmt.Printf("Error reading file content: %v\n", err)
-		return false
-	}
-
-	emails := strings.Split(string(content), "\n")
-	for _, e := range emails {
-		if e == email {
-			return true
-		}
-	}
-	return false
-}
-
-func appendToFile(filename, content string) error {
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err := file.WriteString(content); err != nil {
-		return err
-	}
-	return nil
-}
-
-func sendEmail(to string, verificationLink string) error {
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPassword := os.Getenv("SMTP_PASSWORD")
-
-	if smtpHost == "" || smtpPort == "" || smtpUser == "" || smtpPassword == "" {
-		return fmt.Errorf("SMTP environment variables are not set")
-	}
-
-	subject := "Svelte Commerce Email Verification"
-	body := fmt.Sprintf(`<html><body><h1>Svelte Commerce Email Verification</h1><p>Click the link below to verify your email:</p><a href="%s">Verify Email</a></body></html>`, verificationLink)
-	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\nMIME-Version: 1.0\nContent-Type: text/html; charset=UTF-8\n\n%s", smtpUser, to, subject, body)
-
-	auth := smtp.PlainAuth("", smtpUser, smtpPassword, smtpHost)
-	return smtp.SendMail(fmt.Sprintf("%s:%s", smtpHost, smtpPort), auth, smtpUser, []string{to}, []byte(msg))
+	return verified
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMIMEMessage assembles an RFC 5322 message with a multipart/alternative
+// body (plaintext + HTML) and the headers a well-behaved MTA expects: Date,
+// Message-ID, MIME-Version, and a multipart boundary.
+func buildMIMEMessage(from, to mail.Address, subject, textBody, htmlBody string) ([]byte, error) {
+	if err := rejectHeaderInjection(from.Address, to.Address, subject); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mpw := multipart.NewWriter(&body)
+
+	textPart, err := mpw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=UTF-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := textPart.Write([]byte(textBody)); err != nil {
+		return nil, err
+	}
+
+	htmlPart, err := mpw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/html; charset=UTF-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := htmlPart.Write([]byte(htmlBody)); err != nil {
+		return nil, err
+	}
+
+	if err := mpw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %s\r\n", generateMessageID(from.Address))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n", mpw.Boundary())
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// rejectHeaderInjection fails closed if any header value about to be
+// written into the raw message contains a CR or LF. Go's mail.Address
+// encoder only quotes/escapes values it decides need it (e.g. names with
+// commas), so a crafted single-@ address such as
+// "victim@example.com\nX-Injected: evil" can pass straight through
+// Address.String() and land verbatim in the header block, letting an
+// attacker inject headers or terminate it early. This check must run
+// unconditionally - it can't be gated behind an optional feature flag
+// like the deliverability probe.
+func rejectHeaderInjection(values ...string) error {
+	for _, v := range values {
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("header value contains a CR or LF: %q", v)
+		}
+	}
+	return nil
+}
+
+// generateMessageID builds an RFC 5322 Message-ID using senderAddress's
+// domain, falling back to localhost if it can't be parsed.
+func generateMessageID(senderAddress string) string {
+	domain := "localhost"
+	if parts := strings.SplitN(senderAddress, "@", 2); len(parts) == 2 && parts[1] != "" {
+		domain = parts[1]
+	}
+
+	var entropy [8]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UTC().UnixNano(), domain)
+	}
+
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UTC().UnixNano(), hex.EncodeToString(entropy[:]), domain)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestBuildMIMEMessageRejectsCRLFInToAddress(t *testing.T) {
+	from := mail.Address{Address: "sender@example.com"}
+	to := mail.Address{Address: "victim@example.com\nX-Injected: evil"}
+
+	if _, err := buildMIMEMessage(from, to, "subject", "text body", "<p>html body</p>"); err == nil {
+		t.Fatal("expected buildMIMEMessage to reject a To address containing an embedded LF")
+	}
+}
+
+func TestBuildMIMEMessageRejectsCRLFInSubject(t *testing.T) {
+	from := mail.Address{Address: "sender@example.com"}
+	to := mail.Address{Address: "recipient@example.com"}
+	subject := "Verify your email\r\nX-Injected: evil"
+
+	if _, err := buildMIMEMessage(from, to, subject, "text body", "<p>html body</p>"); err == nil {
+		t.Fatal("expected buildMIMEMessage to reject a subject containing an embedded CRLF")
+	}
+}
+
+func TestBuildMIMEMessageAcceptsCleanAddresses(t *testing.T) {
+	from := mail.Address{Name: "Svelte Commerce", Address: "sender@example.com"}
+	to := mail.Address{Address: "recipient@example.com"}
+
+	msg, err := buildMIMEMessage(from, to, "Verify your email", "text body", "<p>html body</p>")
+	if err != nil {
+		t.Fatalf("buildMIMEMessage returned error: %v", err)
+	}
+
+	raw := string(msg)
+	if !strings.Contains(raw, "To: <recipient@example.com>\r\n") {
+		t.Errorf("expected a clean To header, got:\n%s", raw)
+	}
+	if strings.Contains(raw, "X-Injected") {
+		t.Errorf("message should not contain an injected header, got:\n%s", raw)
+	}
+}
+
+func TestRejectHeaderInjection(t *testing.T) {
+	if err := rejectHeaderInjection("clean@example.com", "a normal subject"); err != nil {
+		t.Fatalf("expected clean values to pass, got error: %v", err)
+	}
+
+	cases := []string{
+		"victim@example.com\nX-Injected: evil",
+		"victim@example.com\r\nX-Injected: evil",
+		"subject with \r a CR",
+	}
+	for _, v := range cases {
+		if err := rejectHeaderInjection(v); err == nil {
+			t.Errorf("expected rejectHeaderInjection to reject %q", v)
+		}
+	}
+}
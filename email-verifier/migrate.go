@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// legacyVerifiedEmailsFile is the flat file previously used to persist
+// verified addresses, kept here only so existing deployments can be
+// migrated into the new VerificationStore on first startup.
+const legacyVerifiedEmailsFile = "verified_emails.txt"
+
+// migrateLegacyVerifiedEmails imports any addresses still sitting in
+// verified_emails.txt into store, then renames the file so it isn't
+// re-imported on subsequent restarts.
+func migrateLegacyVerifiedEmails(store VerificationStore) {
+	content, err := ioutil.ReadFile(legacyVerifiedEmailsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Could not read legacy verified emails file: %v\n", err)
+		}
+		return
+	}
+
+	migrated := 0
+	for _, email := range strings.Split(string(content), "\n") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		if err := store.MarkVerified(email); err != nil {
+			log.Printf("Failed to migrate verified email %q: %v\n", email, err)
+			continue
+		}
+		migrated++
+	}
+
+	migratedPath := legacyVerifiedEmailsFile + ".migrated"
+	if err := os.Rename(legacyVerifiedEmailsFile, migratedPath); err != nil {
+		log.Printf("Migrated %d email(s) but failed to rename legacy file: %v\n", migrated, err)
+		return
+	}
+
+	log.Printf("Migrated %d verified email(s) from %s into the verification store\n", migrated, legacyVerifiedEmailsFile)
+}
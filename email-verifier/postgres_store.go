@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS verification_state (
+	email              TEXT PRIMARY KEY,
+	verified_at        BIGINT,
+	pending_token_hash TEXT,
+	token_expires_at   BIGINT,
+	send_attempts      INTEGER NOT NULL DEFAULT 0,
+	last_sent_at       BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_counters (
+	key               TEXT PRIMARY KEY,
+	window_started_at BIGINT NOT NULL, -- unix millis
+	count             INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// postgresStore is the VerificationStore backend for operators who already
+// run Postgres and would rather not add a SQLite file to back up.
+type postgresStore struct {
+	db *sql.DB
+}
+
+func newPostgresStore(dsn string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres store: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate postgres schema: %w", err)
+	}
+
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) IsVerified(email string) (bool, error) {
+	var verifiedAt sql.NullInt64
+	err := s.db.QueryRow(`SELECT verified_at FROM verification_state WHERE email = $1`, email).Scan(&verifiedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return verifiedAt.Valid, nil
+}
+
+func (s *postgresStore) MarkVerified(email string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO verification_state (email, verified_at, pending_token_hash, token_expires_at)
+		VALUES ($1, $2, NULL, NULL)
+		ON CONFLICT (email) DO UPDATE SET
+			verified_at = excluded.verified_at,
+			pending_token_hash = NULL,
+			token_expires_at = NULL
+	`, email, time.Now().UTC().Unix())
+	return err
+}
+
+func (s *postgresStore) RecordSend(email, tokenHash string, expiresAt time.Time) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.db.Exec(`
+		INSERT INTO verification_state (email, pending_token_hash, token_expires_at, send_attempts, last_sent_at)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (email) DO UPDATE SET
+			pending_token_hash = excluded.pending_token_hash,
+			token_expires_at = excluded.token_expires_at,
+			send_attempts = verification_state.send_attempts + 1,
+			last_sent_at = excluded.last_sent_at
+	`, email, tokenHash, expiresAt.UTC().Unix(), now)
+	return err
+}
+
+func (s *postgresStore) ConsumeToken(email, tokenHash string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE verification_state
+		SET pending_token_hash = NULL
+		WHERE email = $1
+			AND pending_token_hash = $2
+			AND token_expires_at >= $3
+	`, email, tokenHash, time.Now().UTC().Unix())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *postgresStore) IncrementRateLimit(key string, window time.Duration) (int, time.Duration, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Ensure a row exists so the FOR UPDATE below has something to lock even
+	// on a key's first request; a concurrent first insert waits here for the
+	// other transaction to finish.
+	if _, err := tx.Exec(`
+		INSERT INTO rate_limit_counters (key, window_started_at, count)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (key) DO NOTHING
+	`, key, now.UnixMilli()); err != nil {
+		return 0, 0, err
+	}
+
+	var windowStartedAtMillis int64
+	var count int
+	// FOR UPDATE serializes concurrent requests for the same key; unlike
+	// the sqlite backend, Postgres handles real concurrent writers fine.
+	err = tx.QueryRow(`SELECT window_started_at, count FROM rate_limit_counters WHERE key = $1 FOR UPDATE`, key).Scan(&windowStartedAtMillis, &count)
+	switch {
+	case err == sql.ErrNoRows:
+		windowStartedAtMillis = now.UnixMilli()
+		count = 0
+	case err != nil:
+		return 0, 0, err
+	}
+
+	// window_started_at is stored with millisecond resolution so windows
+	// shorter than a second don't get truncated to the wrong floor.
+	windowStart := time.UnixMilli(windowStartedAtMillis).UTC()
+	if now.Sub(windowStart) >= window {
+		windowStart = now
+		count = 0
+	}
+	count++
+
+	if _, err := tx.Exec(`
+		INSERT INTO rate_limit_counters (key, window_started_at, count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			window_started_at = excluded.window_started_at,
+			count = excluded.count
+	`, key, windowStart.UnixMilli(), count); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	resetAfter := window - now.Sub(windowStart)
+	if resetAfter < 0 {
+		resetAfter = 0
+	}
+	return count, resetAfter, nil
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEmailSendLimit  = 3
+	defaultEmailSendWindow = time.Hour
+	defaultIPSendLimit     = 10
+	defaultIPSendWindow    = time.Minute
+)
+
+// rateLimitResult reports whether a send should be throttled and, if so,
+// how long the caller should wait before retrying.
+type rateLimitResult struct {
+	limited    bool
+	retryAfter time.Duration
+}
+
+// checkSendRateLimit enforces both the per-email hourly limit and the
+// per-IP per-minute limit using store-backed counters, so a single
+// attacker can't trigger unlimited outbound mail and limits survive
+// restarts.
+func checkSendRateLimit(store VerificationStore, clientIP, email string) (rateLimitResult, error) {
+	ipCount, ipResetAfter, err := store.IncrementRateLimit("ip:"+clientIP, defaultIPSendWindow)
+	if err != nil {
+		return rateLimitResult{}, fmt.Errorf("failed to check IP rate limit: %w", err)
+	}
+	if ipCount > ipSendLimit() {
+		return rateLimitResult{limited: true, retryAfter: ipResetAfter}, nil
+	}
+
+	emailCount, emailResetAfter, err := store.IncrementRateLimit("email:"+email, defaultEmailSendWindow)
+	if err != nil {
+		return rateLimitResult{}, fmt.Errorf("failed to check email rate limit: %w", err)
+	}
+	if emailCount > emailSendLimit() {
+		return rateLimitResult{limited: true, retryAfter: emailResetAfter}, nil
+	}
+
+	return rateLimitResult{}, nil
+}
+
+func emailSendLimit() int {
+	return envInt("VERIFICATION_EMAIL_RATE_LIMIT", defaultEmailSendLimit)
+}
+
+func ipSendLimit() int {
+	return envInt("VERIFICATION_IP_RATE_LIMIT", defaultIPSendLimit)
+}
+
+func envInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+// clientIPFromRequest returns the address the rate limiter should key on.
+// X-Forwarded-For is only honored when the direct peer (r.RemoteAddr) is
+// inside TRUSTED_PROXY_CIDRS - otherwise any caller could set a fresh XFF
+// value on every request and get a brand-new rate-limit bucket each time,
+// defeating the per-IP limit entirely. With TRUSTED_PROXY_CIDRS unset,
+// this deployment has no trusted proxy in front of it and always falls
+// back to r.RemoteAddr.
+func clientIPFromRequest(r *http.Request) string {
+	remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteHost = r.RemoteAddr
+	}
+
+	fwd := r.Header.Get("X-Forwarded-For")
+	if fwd == "" || !isTrustedProxy(remoteHost) {
+		return remoteHost
+	}
+
+	if client := strings.TrimSpace(strings.Split(fwd, ",")[0]); client != "" {
+		return client
+	}
+	return remoteHost
+}
+
+// isTrustedProxy reports whether host falls within one of the CIDRs listed
+// in TRUSTED_PROXY_CIDRS (comma-separated). Unset/unparseable entries mean
+// no proxy is trusted.
+func isTrustedProxy(host string) bool {
+	raw := os.Getenv("TRUSTED_PROXY_CIDRS")
+	if raw == "" {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range strings.Split(raw, ",") {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
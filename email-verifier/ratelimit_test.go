@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+func withEnv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestClientIPFromRequestDefaultsToRemoteAddr(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/send-verification", nil)
+	req.RemoteAddr = "203.0.113.5:54321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+	if got := clientIPFromRequest(req); got != "203.0.113.5" {
+		t.Fatalf("got %q, want 203.0.113.5 (X-Forwarded-For should be ignored without a trusted proxy)", got)
+	}
+}
+
+func TestClientIPFromRequestHonorsTrustedProxy(t *testing.T) {
+	withEnv(t, "TRUSTED_PROXY_CIDRS", "203.0.113.0/24")
+
+	req, _ := http.NewRequest(http.MethodPost, "/send-verification", nil)
+	req.RemoteAddr = "203.0.113.5:54321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.9, 203.0.113.5")
+
+	if got := clientIPFromRequest(req); got != "198.51.100.9" {
+		t.Fatalf("got %q, want 198.51.100.9 from a trusted proxy's X-Forwarded-For", got)
+	}
+}
+
+func TestClientIPFromRequestIgnoresUntrustedProxy(t *testing.T) {
+	withEnv(t, "TRUSTED_PROXY_CIDRS", "203.0.113.0/24")
+
+	req, _ := http.NewRequest(http.MethodPost, "/send-verification", nil)
+	req.RemoteAddr = "198.51.100.9:54321"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := clientIPFromRequest(req); got != "198.51.100.9" {
+		t.Fatalf("got %q, want 198.51.100.9 (peer is outside TRUSTED_PROXY_CIDRS)", got)
+	}
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	withEnv(t, "TRUSTED_PROXY_CIDRS", "10.0.0.0/8, 203.0.113.0/24")
+
+	cases := map[string]bool{
+		"10.1.2.3":     true,
+		"203.0.113.42": true,
+		"8.8.8.8":      false,
+		"not-an-ip":    false,
+	}
+	for host, want := range cases {
+		if got := isTrustedProxy(host); got != want {
+			t.Errorf("isTrustedProxy(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestIsTrustedProxyUnsetTrustsNothing(t *testing.T) {
+	os.Unsetenv("TRUSTED_PROXY_CIDRS")
+
+	if isTrustedProxy("10.0.0.1") {
+		t.Fatal("expected no proxy to be trusted when TRUSTED_PROXY_CIDRS is unset")
+	}
+}
+
+func TestEnvInt(t *testing.T) {
+	withEnv(t, "VERIFICATION_EMAIL_RATE_LIMIT", "7")
+	if got := envInt("VERIFICATION_EMAIL_RATE_LIMIT", 3); got != 7 {
+		t.Fatalf("got %d, want 7", got)
+	}
+
+	os.Unsetenv("VERIFICATION_EMAIL_RATE_LIMIT")
+	if got := envInt("VERIFICATION_EMAIL_RATE_LIMIT", 3); got != 3 {
+		t.Fatalf("got %d, want fallback 3 when unset", got)
+	}
+
+	withEnv(t, "VERIFICATION_EMAIL_RATE_LIMIT", "not-a-number")
+	if got := envInt("VERIFICATION_EMAIL_RATE_LIMIT", 3); got != 3 {
+		t.Fatalf("got %d, want fallback 3 for an unparseable value", got)
+	}
+}
+
+func TestCheckSendRateLimitBlocksAfterEmailLimit(t *testing.T) {
+	withEnv(t, "VERIFICATION_EMAIL_RATE_LIMIT", "2")
+	withEnv(t, "VERIFICATION_IP_RATE_LIMIT", "100")
+	store := newFakeVerificationStore()
+
+	for i := 0; i < 2; i++ {
+		result, err := checkSendRateLimit(store, "1.2.3.4", "user@example.com")
+		if err != nil {
+			t.Fatalf("checkSendRateLimit: %v", err)
+		}
+		if result.limited {
+			t.Fatalf("attempt %d: expected not limited yet", i+1)
+		}
+	}
+
+	result, err := checkSendRateLimit(store, "1.2.3.4", "user@example.com")
+	if err != nil {
+		t.Fatalf("checkSendRateLimit: %v", err)
+	}
+	if !result.limited {
+		t.Fatal("expected the 3rd send for the same email to be rate limited")
+	}
+	if result.retryAfter <= 0 {
+		t.Fatalf("got retryAfter %v, want a positive duration", result.retryAfter)
+	}
+}
+
+func TestCheckSendRateLimitBlocksAfterIPLimit(t *testing.T) {
+	withEnv(t, "VERIFICATION_EMAIL_RATE_LIMIT", "100")
+	withEnv(t, "VERIFICATION_IP_RATE_LIMIT", "1")
+	store := newFakeVerificationStore()
+
+	if result, err := checkSendRateLimit(store, "1.2.3.4", "first@example.com"); err != nil {
+		t.Fatalf("checkSendRateLimit: %v", err)
+	} else if result.limited {
+		t.Fatal("expected first send from this IP to not be limited")
+	}
+
+	result, err := checkSendRateLimit(store, "1.2.3.4", "second@example.com")
+	if err != nil {
+		t.Fatalf("checkSendRateLimit: %v", err)
+	}
+	if !result.limited {
+		t.Fatal("expected a second email from the same IP to be rate limited")
+	}
+}
+
+func TestCheckSendRateLimitTracksEmailsAndIPsSeparately(t *testing.T) {
+	withEnv(t, "VERIFICATION_EMAIL_RATE_LIMIT", "1")
+	withEnv(t, "VERIFICATION_IP_RATE_LIMIT", "100")
+	store := newFakeVerificationStore()
+
+	if result, err := checkSendRateLimit(store, "1.2.3.4", "user@example.com"); err != nil {
+		t.Fatalf("checkSendRateLimit: %v", err)
+	} else if result.limited {
+		t.Fatal("expected first send to not be limited")
+	}
+
+	if result, err := checkSendRateLimit(store, "5.6.7.8", "other@example.com"); err != nil {
+		t.Fatalf("checkSendRateLimit: %v", err)
+	} else if result.limited {
+		t.Fatal("a different email/IP pair should have its own counters")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisStateKeyPrefix = "verify:state:"
+	redisRateKeyPrefix  = "verify:rate:"
+)
+
+// errTokenNotConsumable signals that ConsumeToken's optimistic transaction
+// found no matching, unexpired pending token - a normal "reject" outcome,
+// not a storage failure.
+var errTokenNotConsumable = errors.New("token is unknown, expired, or already consumed")
+
+// redisStore is the VerificationStore backend for operators who already
+// run Redis, e.g. alongside a session store. Verification state lives in
+// one hash per email; rate-limit counters use Redis's native key TTL to
+// implement the fixed window without a background sweep.
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(addr, password string, db int) (*redisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &redisStore{client: client}, nil
+}
+
+func (s *redisStore) IsVerified(email string) (bool, error) {
+	ctx := context.Background()
+	val, err := s.client.HGet(ctx, redisStateKeyPrefix+email, "verified_at").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return val != "", nil
+}
+
+func (s *redisStore) MarkVerified(email string) error {
+	ctx := context.Background()
+	key := redisStateKeyPrefix + email
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "verified_at", time.Now().UTC().Unix())
+		pipe.HDel(ctx, key, "pending_token_hash", "token_expires_at")
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) RecordSend(email, tokenHash string, expiresAt time.Time) error {
+	ctx := context.Background()
+	key := redisStateKeyPrefix + email
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"pending_token_hash": tokenHash,
+			"token_expires_at":   expiresAt.UTC().Unix(),
+			"last_sent_at":       time.Now().UTC().Unix(),
+		})
+		pipe.HIncrBy(ctx, key, "send_attempts", 1)
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) ConsumeToken(email, tokenHash string) (bool, error) {
+	ctx := context.Background()
+	key := redisStateKeyPrefix + email
+
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		values, err := tx.HMGet(ctx, key, "pending_token_hash", "token_expires_at").Result()
+		if err != nil {
+			return err
+		}
+
+		storedHash, _ := values[0].(string)
+		if storedHash == "" || storedHash != tokenHash {
+			return errTokenNotConsumable
+		}
+
+		expiresAtStr, _ := values[1].(string)
+		expiresAt, err := strconv.ParseInt(expiresAtStr, 10, 64)
+		if err != nil || time.Now().UTC().Unix() > expiresAt {
+			return errTokenNotConsumable
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, key, "pending_token_hash")
+			return nil
+		})
+		return err
+	}, key)
+
+	if errors.Is(err, errTokenNotConsumable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *redisStore) IncrementRateLimit(key string, window time.Duration) (int, time.Duration, error) {
+	ctx := context.Background()
+	fullKey := redisRateKeyPrefix + key
+
+	count, err := s.client.Incr(ctx, fullKey).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if count == 1 {
+		if err := s.client.Expire(ctx, fullKey, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		return int(count), window, nil
+	}
+
+	ttl, err := s.client.TTL(ctx, fullKey).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ttl < 0 {
+		// No TTL somehow survived (e.g. restored from a backup) - reset it
+		// rather than letting the counter grow unbounded.
+		if err := s.client.Expire(ctx, fullKey, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		ttl = window
+	}
+
+	return int(count), ttl, nil
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
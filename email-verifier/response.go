@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// writeJSONError writes a JSON error body of the form {"code": "...",
+// "message": "..."} with the given HTTP status, matching the error code
+// shape used across the verification endpoints.
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": message,
+	})
+}
+
+// writeRateLimitError writes a 429 response with a Retry-After header and
+// the {"code":"too-many-requests","retry_after_seconds":N} body.
+func writeRateLimitError(w http.ResponseWriter, retryAfter time.Duration) {
+	retrySeconds := int(retryAfter.Seconds())
+	if retrySeconds < 1 {
+		retrySeconds = 1
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retrySeconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":                "too-many-requests",
+		"retry_after_seconds": retrySeconds,
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS verification_state (
+	email              TEXT PRIMARY KEY,
+	verified_at        INTEGER,
+	pending_token_hash TEXT,
+	token_expires_at   INTEGER,
+	send_attempts      INTEGER NOT NULL DEFAULT 0,
+	last_sent_at       INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_counters (
+	key               TEXT PRIMARY KEY,
+	window_started_at INTEGER NOT NULL, -- unix millis
+	count             INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// sqliteStore is the default VerificationStore backend. It requires no
+// external service, which keeps single-instance self-hosting simple.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite store at %s: %w", path, err)
+	}
+
+	// modernc.org/sqlite hands out a real connection per goroutine rather
+	// than serializing access internally, so concurrent writers (e.g. two
+	// /send-verification requests hitting IncrementRateLimit at once) can
+	// collide mid-transaction. SQLite only supports one writer at a time
+	// regardless, so pin the pool to a single connection and let the
+	// standard library's *sql.DB queue the rest.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite schema: %w", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) IsVerified(email string) (bool, error) {
+	var verifiedAt sql.NullInt64
+	err := s.db.QueryRow(`SELECT verified_at FROM verification_state WHERE email = ?`, email).Scan(&verifiedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return verifiedAt.Valid, nil
+}
+
+func (s *sqliteStore) MarkVerified(email string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO verification_state (email, verified_at, pending_token_hash, token_expires_at)
+		VALUES (?, ?, NULL, NULL)
+		ON CONFLICT(email) DO UPDATE SET
+			verified_at = excluded.verified_at,
+			pending_token_hash = NULL,
+			token_expires_at = NULL
+	`, email, time.Now().UTC().Unix())
+	return err
+}
+
+func (s *sqliteStore) RecordSend(email, tokenHash string, expiresAt time.Time) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.db.Exec(`
+		INSERT INTO verification_state (email, pending_token_hash, token_expires_at, send_attempts, last_sent_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT(email) DO UPDATE SET
+			pending_token_hash = excluded.pending_token_hash,
+			token_expires_at = excluded.token_expires_at,
+			send_attempts = verification_state.send_attempts + 1,
+			last_sent_at = excluded.last_sent_at
+	`, email, tokenHash, expiresAt.UTC().Unix(), now)
+	return err
+}
+
+func (s *sqliteStore) ConsumeToken(email, tokenHash string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE verification_state
+		SET pending_token_hash = NULL
+		WHERE email = ?
+			AND pending_token_hash = ?
+			AND token_expires_at >= ?
+	`, email, tokenHash, time.Now().UTC().Unix())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *sqliteStore) IncrementRateLimit(key string, window time.Duration) (int, time.Duration, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var windowStartedAtMillis int64
+	var count int
+	err = tx.QueryRow(`SELECT window_started_at, count FROM rate_limit_counters WHERE key = ?`, key).Scan(&windowStartedAtMillis, &count)
+	switch {
+	case err == sql.ErrNoRows:
+		windowStartedAtMillis = now.UnixMilli()
+		count = 0
+	case err != nil:
+		return 0, 0, err
+	}
+
+	// window_started_at is stored with millisecond resolution - truncating
+	// to whole seconds (time.Unix's second argument) made windows shorter
+	// than ~1s compare against the wrong floor and reset early or late.
+	windowStart := time.UnixMilli(windowStartedAtMillis).UTC()
+	if now.Sub(windowStart) >= window {
+		windowStart = now
+		count = 0
+	}
+	count++
+
+	if _, err := tx.Exec(`
+		INSERT INTO rate_limit_counters (key, window_started_at, count)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			window_started_at = excluded.window_started_at,
+			count = excluded.count
+	`, key, windowStart.UnixMilli(), count); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	resetAfter := window - now.Sub(windowStart)
+	if resetAfter < 0 {
+		resetAfter = 0
+	}
+	return count, resetAfter, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
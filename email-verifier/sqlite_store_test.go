@@ -0,0 +1,152 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteStore(t *testing.T) *sqliteStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "verification.db")
+	store, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStoreMarkVerifiedClearsPendingToken(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	email := "user@example.com"
+
+	if err := store.RecordSend(email, "hash-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RecordSend: %v", err)
+	}
+	if err := store.MarkVerified(email); err != nil {
+		t.Fatalf("MarkVerified: %v", err)
+	}
+
+	verified, err := store.IsVerified(email)
+	if err != nil {
+		t.Fatalf("IsVerified: %v", err)
+	}
+	if !verified {
+		t.Fatal("expected email to be verified")
+	}
+
+	if consumed, err := store.ConsumeToken(email, "hash-1"); err != nil {
+		t.Fatalf("ConsumeToken: %v", err)
+	} else if consumed {
+		t.Fatal("expected pending token to have been cleared by MarkVerified")
+	}
+}
+
+func TestSQLiteStoreConsumeTokenIsSingleUse(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	email := "user@example.com"
+
+	if err := store.RecordSend(email, "hash-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RecordSend: %v", err)
+	}
+
+	consumed, err := store.ConsumeToken(email, "hash-1")
+	if err != nil {
+		t.Fatalf("ConsumeToken: %v", err)
+	}
+	if !consumed {
+		t.Fatal("expected first ConsumeToken to succeed")
+	}
+
+	consumed, err = store.ConsumeToken(email, "hash-1")
+	if err != nil {
+		t.Fatalf("ConsumeToken: %v", err)
+	}
+	if consumed {
+		t.Fatal("expected second ConsumeToken of the same hash to fail")
+	}
+}
+
+func TestSQLiteStoreConsumeTokenRejectsExpired(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	email := "user@example.com"
+
+	if err := store.RecordSend(email, "hash-1", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordSend: %v", err)
+	}
+
+	consumed, err := store.ConsumeToken(email, "hash-1")
+	if err != nil {
+		t.Fatalf("ConsumeToken: %v", err)
+	}
+	if consumed {
+		t.Fatal("expected ConsumeToken to reject an already-expired token")
+	}
+}
+
+func TestSQLiteStoreIncrementRateLimitResetsAfterWindow(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	count, _, err := store.IncrementRateLimit("ip:1.2.3.4", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("IncrementRateLimit: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("got count %d, want 1", count)
+	}
+
+	count, _, err = store.IncrementRateLimit("ip:1.2.3.4", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("IncrementRateLimit: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("got count %d, want 2", count)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	count, _, err = store.IncrementRateLimit("ip:1.2.3.4", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("IncrementRateLimit: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("got count %d after window elapsed, want 1", count)
+	}
+}
+
+// TestSQLiteStoreIncrementRateLimitUnderConcurrency is a regression test for
+// the SQLITE_BUSY errors produced by concurrent writers before newSQLiteStore
+// pinned the pool to a single connection and set a busy_timeout.
+func TestSQLiteStoreIncrementRateLimitUnderConcurrency(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	const writers = 50
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := store.IncrementRateLimit("ip:shared", time.Minute)
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("writer %d: IncrementRateLimit failed: %v", i, err)
+		}
+	}
+
+	count, _, err := store.IncrementRateLimit("ip:shared", time.Minute)
+	if err != nil {
+		t.Fatalf("IncrementRateLimit: %v", err)
+	}
+	if count != writers+1 {
+		t.Fatalf("got count %d, want %d", count, writers+1)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// VerificationStore persists the state of the email-verification flow:
+// whether an address has completed verification, the hash and expiry of
+// whichever token is currently outstanding for it, and send bookkeeping
+// used for rate limiting. It replaces the old verified_emails.txt flat
+// file, which had no locking around append+read, O(n) lookups, and no
+// way to store token or attempt state.
+type VerificationStore interface {
+	// IsVerified reports whether email has completed verification.
+	IsVerified(email string) (bool, error)
+
+	// MarkVerified records email as verified and clears any pending token.
+	MarkVerified(email string) error
+
+	// RecordSend stores the hash of a newly issued verification token
+	// along with its expiry, and bumps the send-attempt counter and
+	// last-sent timestamp. Rate limiting itself uses IncrementRateLimit.
+	RecordSend(email, tokenHash string, expiresAt time.Time) error
+
+	// ConsumeToken checks tokenHash against the pending token for email.
+	// If it matches, hasn't expired, and hasn't already been consumed, it
+	// clears the pending token (so it can't be redeemed twice) and returns
+	// true; otherwise it returns false.
+	ConsumeToken(email, tokenHash string) (bool, error)
+
+	// IncrementRateLimit increments the counter for key within a fixed
+	// window of the given duration, resetting it if the window has
+	// elapsed. It returns the updated count and how long until the
+	// window resets. Counters are persisted so limits survive restarts.
+	IncrementRateLimit(key string, window time.Duration) (count int, resetAfter time.Duration, err error)
+
+	Close() error
+}
+
+// newVerificationStore selects a VerificationStore implementation based
+// on VERIFICATION_STORE_BACKEND, defaulting to sqlite.
+func newVerificationStore() (VerificationStore, error) {
+	backend := os.Getenv("VERIFICATION_STORE_BACKEND")
+	if backend == "" {
+		backend = "sqlite"
+	}
+
+	switch backend {
+	case "sqlite":
+		return newSQLiteStore(sqliteStorePath())
+	case "postgres":
+		dsn := os.Getenv("POSTGRES_DSN")
+		if dsn == "" {
+			return nil, fmt.Errorf("POSTGRES_DSN must be set when VERIFICATION_STORE_BACKEND=postgres")
+		}
+		return newPostgresStore(dsn)
+	case "redis":
+		return newRedisStore(redisAddr(), os.Getenv("REDIS_PASSWORD"), redisDB())
+	default:
+		return nil, fmt.Errorf("unsupported VERIFICATION_STORE_BACKEND %q", backend)
+	}
+}
+
+func sqliteStorePath() string {
+	if path := os.Getenv("VERIFICATION_STORE_PATH"); path != "" {
+		return path
+	}
+	return "verification.db"
+}
+
+func redisAddr() string {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return addr
+	}
+	return "localhost:6379"
+}
+
+func redisDB() int {
+	raw := os.Getenv("REDIS_DB")
+	if raw == "" {
+		return 0
+	}
+	db, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return db
+}
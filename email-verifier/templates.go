@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+const (
+	templateVerification  = "verification"
+	templateWelcome       = "welcome"
+	templatePasswordReset = "password_reset"
+)
+
+// templateNames lists every transactional email template loadEmailTemplates
+// parses at startup.
+var templateNames = []string{templateVerification, templateWelcome, templatePasswordReset}
+
+// EmailData is the set of variables available to every transactional email
+// template.
+type EmailData struct {
+	VerificationLink string
+	BrandName        string
+	ExpiresIn        string
+	SupportEmail     string
+}
+
+// parsedTemplate holds the compiled HTML and plaintext variants of a single
+// named email template.
+type parsedTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// emailTemplates holds every template parsed by loadEmailTemplates, keyed by
+// name. It's populated once at startup so a broken or missing template
+// fails fast instead of surfacing on the first live send.
+var emailTemplates map[string]parsedTemplate
+
+// templateDir returns the directory transactional email templates are
+// loaded from, defaulting to ./templates but overridable so operators can
+// customize branding without recompiling.
+func templateDir() string {
+	if dir := os.Getenv("TEMPLATE_DIR"); dir != "" {
+		return dir
+	}
+	return "templates"
+}
+
+// loadEmailTemplates parses every template in templateNames from
+// templateDir() and populates emailTemplates. It's called once from main()
+// so a broken or missing template is a startup failure, not a failure on
+// the first verification send.
+func loadEmailTemplates() error {
+	dir := templateDir()
+	loaded := make(map[string]parsedTemplate, len(templateNames))
+
+	for _, name := range templateNames {
+		htmlTmpl, err := htmltemplate.ParseFiles(filepath.Join(dir, name+".html"))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s.html: %w", name, err)
+		}
+
+		textTmpl, err := texttemplate.ParseFiles(filepath.Join(dir, name+".txt"))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s.txt: %w", name, err)
+		}
+
+		loaded[name] = parsedTemplate{html: htmlTmpl, text: textTmpl}
+	}
+
+	emailTemplates = loaded
+	return nil
+}
+
+// renderEmail renders both the HTML and plaintext variants of the named
+// template (e.g. "verification") for use in a multipart/alternative
+// message, using the templates loadEmailTemplates compiled at startup.
+func renderEmail(name string, data EmailData) (htmlBody, textBody string, err error) {
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return "", "", fmt.Errorf("no template loaded for %q", name)
+	}
+
+	var htmlBuf bytes.Buffer
+	if err := tmpl.html.Execute(&htmlBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s.html: %w", name, err)
+	}
+
+	var textBuf bytes.Buffer
+	if err := tmpl.text.Execute(&textBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s.txt: %w", name, err)
+	}
+
+	return htmlBuf.String(), textBuf.String(), nil
+}
+
+func brandName() string {
+	if name := os.Getenv("BRAND_NAME"); name != "" {
+		return name
+	}
+	return "Svelte Commerce"
+}
+
+func supportEmail() string {
+	if email := os.Getenv("SUPPORT_EMAIL"); email != "" {
+		return email
+	}
+	return "support@svelte-commerce.local"
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultVerificationTTL is used when VERIFICATION_TOKEN_TTL is unset or invalid.
+const defaultVerificationTTL = 24 * time.Hour
+
+// tokenError is returned by token generation/validation and carries the
+// stable error code expected by callers (e.g. verifyEmailHandler).
+type tokenError struct {
+	code    string
+	message string
+}
+
+func (e *tokenError) Error() string { return e.message }
+
+func newTokenError(code, message string) *tokenError {
+	return &tokenError{code: code, message: message}
+}
+
+func verificationTTL() time.Duration {
+	if raw := os.Getenv("VERIFICATION_TOKEN_TTL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+		fmt.Printf("Invalid VERIFICATION_TOKEN_TTL %q, falling back to %s\n", raw, defaultVerificationTTL)
+	}
+	return defaultVerificationTTL
+}
+
+func signingKey() ([]byte, error) {
+	key := os.Getenv("VERIFICATION_SIGNING_KEY")
+	if key == "" {
+		return nil, fmt.Errorf("VERIFICATION_SIGNING_KEY is not set")
+	}
+	return []byte(key), nil
+}
+
+// generateVerificationToken creates a signed, single-use, expiring token for
+// email and records its hash in store so it can later be redeemed exactly
+// once. The token encodes (email, nonce, issuedAt, expiresAt) and is signed
+// with HMAC-SHA256 over a server-side secret so it can't be forged or
+// replayed after expiry.
+func generateVerificationToken(store VerificationStore, email string) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	now := time.Now().UTC()
+	issuedAt := now.UnixMilli()
+	expiresAt := now.Add(verificationTTL()).UnixMilli()
+
+	payload := encodeTokenPayload(email, base64.RawURLEncoding.EncodeToString(nonce), issuedAt, expiresAt)
+	sig := signPayload(key, payload)
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	if err := store.RecordSend(email, hashToken(token), time.UnixMilli(expiresAt).UTC()); err != nil {
+		return "", fmt.Errorf("failed to record issued token: %w", err)
+	}
+
+	return token, nil
+}
+
+// parseVerificationToken validates token's signature and expiry, then
+// consumes it in store so it can't be redeemed a second time. It returns
+// the email the token was issued for.
+func parseVerificationToken(store VerificationStore, token string) (string, *tokenError) {
+	key, err := signingKey()
+	if err != nil {
+		return "", newTokenError("invalid-token", err.Error())
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return "", newTokenError("invalid-token", "malformed token")
+	}
+	payload, encodedSig := parts[0], parts[1]
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", newTokenError("invalid-token", "malformed token signature")
+	}
+	if !hmac.Equal(sig, signPayload(key, payload)) {
+		return "", newTokenError("invalid-token", "token signature mismatch")
+	}
+
+	email, _, _, expiresAt, err := decodeTokenPayload(payload)
+	if err != nil {
+		return "", newTokenError("invalid-token", err.Error())
+	}
+
+	// expiresAt is stored with millisecond resolution - truncating to whole
+	// seconds made short TTLs (e.g. tests using a millisecond-scale
+	// VERIFICATION_TOKEN_TTL) round down to "already expired" immediately.
+	if time.Now().UTC().After(time.UnixMilli(expiresAt).UTC()) {
+		return "", newTokenError("token-expired", "verification token has expired")
+	}
+
+	consumed, err := store.ConsumeToken(email, hashToken(token))
+	if err != nil {
+		return "", newTokenError("invalid-token", fmt.Sprintf("failed to consume token: %v", err))
+	}
+	if !consumed {
+		return "", newTokenError("invalid-token", "verification token is unknown or has already been used")
+	}
+
+	return email, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signPayload(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func encodeTokenPayload(email, nonce string, issuedAt, expiresAt int64) string {
+	raw := strings.Join([]string{
+		email,
+		nonce,
+		strconv.FormatInt(issuedAt, 10),
+		strconv.FormatInt(expiresAt, 10),
+	}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTokenPayload(payload string) (email, nonce string, issuedAt, expiresAt int64, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", 0, 0, fmt.Errorf("malformed token payload")
+	}
+
+	fields := strings.Split(string(raw), "|")
+	if len(fields) != 4 {
+		return "", "", 0, 0, fmt.Errorf("malformed token payload")
+	}
+
+	issuedAt, err = strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", "", 0, 0, fmt.Errorf("malformed token payload")
+	}
+	expiresAt, err = strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return "", "", 0, 0, fmt.Errorf("malformed token payload")
+	}
+
+	return fields[0], fields[1], issuedAt, expiresAt, nil
+}
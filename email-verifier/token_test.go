@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVerificationStore is an in-memory VerificationStore used across this
+// package's tests so they don't need a real sqlite/postgres/redis backend.
+type fakeVerificationStore struct {
+	verified     map[string]bool
+	pendingHash  map[string]string
+	pendingExp   map[string]int64
+	rateCounters map[string]struct {
+		windowStart time.Time
+		count       int
+	}
+}
+
+func newFakeVerificationStore() *fakeVerificationStore {
+	return &fakeVerificationStore{
+		verified:    make(map[string]bool),
+		pendingHash: make(map[string]string),
+		pendingExp:  make(map[string]int64),
+		rateCounters: make(map[string]struct {
+			windowStart time.Time
+			count       int
+		}),
+	}
+}
+
+func (s *fakeVerificationStore) IsVerified(email string) (bool, error) {
+	return s.verified[email], nil
+}
+
+func (s *fakeVerificationStore) MarkVerified(email string) error {
+	s.verified[email] = true
+	delete(s.pendingHash, email)
+	delete(s.pendingExp, email)
+	return nil
+}
+
+func (s *fakeVerificationStore) RecordSend(email, tokenHash string, expiresAt time.Time) error {
+	s.pendingHash[email] = tokenHash
+	s.pendingExp[email] = expiresAt.UTC().Unix()
+	return nil
+}
+
+func (s *fakeVerificationStore) ConsumeToken(email, tokenHash string) (bool, error) {
+	stored, ok := s.pendingHash[email]
+	if !ok || stored != tokenHash {
+		return false, nil
+	}
+	if time.Now().UTC().Unix() > s.pendingExp[email] {
+		return false, nil
+	}
+	delete(s.pendingHash, email)
+	return true, nil
+}
+
+func (s *fakeVerificationStore) IncrementRateLimit(key string, window time.Duration) (int, time.Duration, error) {
+	now := time.Now().UTC()
+	entry := s.rateCounters[key]
+	if entry.windowStart.IsZero() || now.Sub(entry.windowStart) >= window {
+		entry.windowStart = now
+		entry.count = 0
+	}
+	entry.count++
+	s.rateCounters[key] = entry
+	return entry.count, window - now.Sub(entry.windowStart), nil
+}
+
+func (s *fakeVerificationStore) Close() error { return nil }
+
+func withSigningKey(t *testing.T, key string) {
+	t.Helper()
+	prev, had := os.LookupEnv("VERIFICATION_SIGNING_KEY")
+	os.Setenv("VERIFICATION_SIGNING_KEY", key)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv("VERIFICATION_SIGNING_KEY", prev)
+		} else {
+			os.Unsetenv("VERIFICATION_SIGNING_KEY")
+		}
+	})
+}
+
+func TestGenerateAndParseVerificationToken(t *testing.T) {
+	withSigningKey(t, "test-signing-key")
+	store := newFakeVerificationStore()
+
+	token, err := generateVerificationToken(store, "user@example.com")
+	if err != nil {
+		t.Fatalf("generateVerificationToken returned error: %v", err)
+	}
+
+	email, tokErr := parseVerificationToken(store, token)
+	if tokErr != nil {
+		t.Fatalf("parseVerificationToken returned error: %v", tokErr)
+	}
+	if email != "user@example.com" {
+		t.Fatalf("got email %q, want user@example.com", email)
+	}
+}
+
+func TestParseVerificationTokenRejectsReuse(t *testing.T) {
+	withSigningKey(t, "test-signing-key")
+	store := newFakeVerificationStore()
+
+	token, err := generateVerificationToken(store, "user@example.com")
+	if err != nil {
+		t.Fatalf("generateVerificationToken returned error: %v", err)
+	}
+
+	if _, tokErr := parseVerificationToken(store, token); tokErr != nil {
+		t.Fatalf("first parse should succeed, got error: %v", tokErr)
+	}
+
+	if _, tokErr := parseVerificationToken(store, token); tokErr == nil {
+		t.Fatal("second parse of the same token should fail, got nil error")
+	} else if tokErr.code != "invalid-token" {
+		t.Fatalf("got code %q, want invalid-token", tokErr.code)
+	}
+}
+
+func TestParseVerificationTokenRejectsTamperedSignature(t *testing.T) {
+	withSigningKey(t, "test-signing-key")
+	store := newFakeVerificationStore()
+
+	token, err := generateVerificationToken(store, "user@example.com")
+	if err != nil {
+		t.Fatalf("generateVerificationToken returned error: %v", err)
+	}
+
+	// Replace the first signature character: unlike the last one, it carries
+	// six full bits, so any different character changes the decoded bytes.
+	sigStart := strings.LastIndex(token, ".") + 1
+	replacement := "A"
+	if token[sigStart] == 'A' {
+		replacement = "B"
+	}
+	tampered := token[:sigStart] + replacement + token[sigStart+1:]
+	if _, tokErr := parseVerificationToken(store, tampered); tokErr == nil {
+		t.Fatal("tampered token should fail to parse, got nil error")
+	} else if tokErr.code != "invalid-token" {
+		t.Fatalf("got code %q, want invalid-token", tokErr.code)
+	}
+}
+
+func TestParseVerificationTokenRejectsExpired(t *testing.T) {
+	withSigningKey(t, "test-signing-key")
+	os.Setenv("VERIFICATION_TOKEN_TTL", "1ms")
+	t.Cleanup(func() { os.Unsetenv("VERIFICATION_TOKEN_TTL") })
+
+	store := newFakeVerificationStore()
+	token, err := generateVerificationToken(store, "user@example.com")
+	if err != nil {
+		t.Fatalf("generateVerificationToken returned error: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, tokErr := parseVerificationToken(store, token); tokErr == nil {
+		t.Fatal("expired token should fail to parse, got nil error")
+	} else if tokErr.code != "token-expired" {
+		t.Fatalf("got code %q, want token-expired", tokErr.code)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// accountVerificationMode controls how a newly submitted email reaches the
+// "verified" state.
+type accountVerificationMode string
+
+const (
+	// modeAllowAll auto-verifies every email without sending mail. Dev only.
+	modeAllowAll accountVerificationMode = "AllowAll"
+	// modeWhitelist only allows emails present in ACCOUNT_WHITELIST(_FILE)
+	// to request verification.
+	modeWhitelist accountVerificationMode = "Whitelist"
+	// modeEmailVerify is today's behavior: send a signed verification link.
+	modeEmailVerify accountVerificationMode = "EmailVerify"
+)
+
+// verificationMode reads ACCOUNT_VERIFICATION_MODE, defaulting to EmailVerify.
+func verificationMode() accountVerificationMode {
+	switch mode := accountVerificationMode(os.Getenv("ACCOUNT_VERIFICATION_MODE")); mode {
+	case modeAllowAll, modeWhitelist, modeEmailVerify:
+		return mode
+	default:
+		return modeEmailVerify
+	}
+}
+
+// warnIfAllowAll logs a prominent startup warning when auto-verification is
+// enabled, since it's only meant for local development.
+func warnIfAllowAll() {
+	if verificationMode() == modeAllowAll {
+		log.Println("WARNING: ACCOUNT_VERIFICATION_MODE=AllowAll is enabled - every signup is auto-verified without sending mail. Do not use this in production.")
+	}
+}
+
+// isWhitelisted reports whether email appears in ACCOUNT_WHITELIST or
+// ACCOUNT_WHITELIST_FILE.
+func isWhitelisted(email string) (bool, error) {
+	entries, err := loadWhitelist()
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func loadWhitelist() ([]string, error) {
+	var entries []string
+
+	if raw := os.Getenv("ACCOUNT_WHITELIST"); raw != "" {
+		for _, e := range strings.Split(raw, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	if path := os.Getenv("ACCOUNT_WHITELIST_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ACCOUNT_WHITELIST_FILE: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				entries = append(entries, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read ACCOUNT_WHITELIST_FILE: %w", err)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,27 @@
+package verifier
+
+// disposableDomains is a small bundled list of well-known disposable/burner
+// email providers. It's intentionally not exhaustive - it catches the
+// common cases without requiring a network call.
+var disposableDomains = map[string]struct{}{
+	"mailinator.com":    {},
+	"tempmail.com":      {},
+	"temp-mail.org":     {},
+	"10minutemail.com":  {},
+	"guerrillamail.com": {},
+	"yopmail.com":       {},
+	"trashmail.com":     {},
+	"getnada.com":       {},
+	"throwawaymail.com": {},
+	"fakeinbox.com":     {},
+	"dispostable.com":   {},
+	"sharklasers.com":   {},
+	"maildrop.cc":       {},
+}
+
+// IsDisposableDomain reports whether domain belongs to a known disposable
+// email provider.
+func IsDisposableDomain(domain string) bool {
+	_, ok := disposableDomains[domain]
+	return ok
+}
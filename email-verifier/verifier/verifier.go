@@ -0,0 +1,152 @@
+// Package verifier checks whether an email address is likely to be
+// deliverable before the caller spends a send on it: syntax validation,
+// an MX lookup, and an optional live SMTP RCPT probe.
+package verifier
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reachability summarizes how likely an address is to accept mail.
+type Reachability string
+
+const (
+	ReachabilityYes     Reachability = "yes"
+	ReachabilityNo      Reachability = "no"
+	ReachabilityUnknown Reachability = "unknown"
+)
+
+// Result is the outcome of checking a single email address.
+type Result struct {
+	Email        string
+	Syntax       bool
+	Disposable   bool
+	MXRecords    []string
+	Reachability Reachability
+}
+
+// Options configures how deep Check goes.
+type Options struct {
+	// HelloName is sent in the SMTP greeting during the RCPT probe. net/smtp
+	// sends it with EHLO and uses HELO only if the server refuses EHLO.
+	HelloName string
+	// FromEmail is used as the MAIL FROM address during the RCPT probe.
+	FromEmail string
+	// EnableSMTPProbe turns on the live RCPT TO probe. Off by default
+	// since many networks block outbound port 25.
+	EnableSMTPProbe bool
+	// DialTimeout bounds the SMTP probe connection.
+	DialTimeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.HelloName == "" {
+		o.HelloName = "localhost"
+	}
+	if o.FromEmail == "" {
+		o.FromEmail = "verify@localhost"
+	}
+	if o.DialTimeout == 0 {
+		o.DialTimeout = 10 * time.Second
+	}
+	return o
+}
+
+// Check runs syntax validation, an MX lookup, a disposable-domain check,
+// and (if enabled) a live SMTP RCPT probe against email.
+func Check(email string, opts Options) Result {
+	opts = opts.withDefaults()
+	result := Result{Email: email, Reachability: ReachabilityUnknown}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return result
+	}
+	result.Syntax = true
+
+	domain := domainOf(addr.Address)
+	result.Disposable = IsDisposableDomain(domain)
+
+	mxHosts, err := lookupMX(domain)
+	if err != nil || len(mxHosts) == 0 {
+		result.Reachability = ReachabilityNo
+		return result
+	}
+	result.MXRecords = mxHosts
+
+	if !opts.EnableSMTPProbe {
+		return result
+	}
+
+	result.Reachability = probeRCPT(mxHosts[0], opts, addr.Address)
+	return result
+}
+
+func domainOf(email string) string {
+	parts := strings.SplitN(email, "@", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return strings.ToLower(parts[1])
+}
+
+func lookupMX(domain string) ([]string, error) {
+	records, err := net.LookupMX(domain)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Pref < records[j].Pref })
+
+	hosts := make([]string, 0, len(records))
+	for _, r := range records {
+		hosts = append(hosts, strings.TrimSuffix(r.Host, "."))
+	}
+	return hosts, nil
+}
+
+// probeRCPT dials host on port 25 and walks EHLO (or HELO)/MAIL FROM/RCPT TO to see
+// how the remote server reacts to the address, without sending a message.
+func probeRCPT(host string, opts Options, to string) Reachability {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:25", host), opts.DialTimeout)
+	if err != nil {
+		return ReachabilityUnknown
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return ReachabilityUnknown
+	}
+	defer client.Close()
+
+	if err := client.Hello(opts.HelloName); err != nil {
+		return ReachabilityUnknown
+	}
+	if err := client.Mail(opts.FromEmail); err != nil {
+		return ReachabilityUnknown
+	}
+
+	err = client.Rcpt(to)
+	client.Quit()
+
+	if err == nil {
+		return ReachabilityYes
+	}
+
+	if protoErr, ok := err.(*textproto.Error); ok {
+		switch {
+		case protoErr.Code == 550:
+			return ReachabilityNo
+		case protoErr.Code >= 400 && protoErr.Code < 500:
+			return ReachabilityUnknown
+		}
+	}
+	return ReachabilityUnknown
+}
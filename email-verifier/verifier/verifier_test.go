@@ -0,0 +1,83 @@
+package verifier
+
+import "testing"
+
+func TestCheckRejectsInvalidSyntax(t *testing.T) {
+	result := Check("not-an-email", Options{})
+
+	if result.Syntax {
+		t.Fatal("expected Syntax to be false for a malformed address")
+	}
+	if result.Reachability != ReachabilityUnknown {
+		t.Fatalf("got reachability %q, want %q for a syntax failure", result.Reachability, ReachabilityUnknown)
+	}
+	if len(result.MXRecords) != 0 {
+		t.Fatalf("expected no MX records to be looked up, got %v", result.MXRecords)
+	}
+}
+
+func TestCheckFlagsDisposableDomain(t *testing.T) {
+	result := Check("user@mailinator.com", Options{})
+
+	if !result.Syntax {
+		t.Fatal("expected Syntax to be true for a well-formed address")
+	}
+	if !result.Disposable {
+		t.Fatal("expected mailinator.com to be flagged as disposable")
+	}
+}
+
+func TestCheckDoesNotProbeSMTPByDefault(t *testing.T) {
+	result := Check("user@example.com", Options{})
+
+	if result.Reachability == ReachabilityYes {
+		t.Fatal("expected no RCPT probe to run when EnableSMTPProbe is false")
+	}
+}
+
+func TestIsDisposableDomain(t *testing.T) {
+	cases := map[string]bool{
+		"mailinator.com":    true,
+		"guerrillamail.com": true,
+		"example.com":       false,
+		"gmail.com":         false,
+	}
+
+	for domain, want := range cases {
+		if got := IsDisposableDomain(domain); got != want {
+			t.Errorf("IsDisposableDomain(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
+
+func TestOptionsWithDefaults(t *testing.T) {
+	opts := Options{}.withDefaults()
+
+	if opts.HelloName != "localhost" {
+		t.Errorf("got HelloName %q, want localhost", opts.HelloName)
+	}
+	if opts.FromEmail != "verify@localhost" {
+		t.Errorf("got FromEmail %q, want verify@localhost", opts.FromEmail)
+	}
+	if opts.DialTimeout <= 0 {
+		t.Errorf("got DialTimeout %v, want a positive default", opts.DialTimeout)
+	}
+
+	custom := Options{HelloName: "mail.example.com"}.withDefaults()
+	if custom.HelloName != "mail.example.com" {
+		t.Errorf("withDefaults overwrote an explicitly set HelloName: got %q", custom.HelloName)
+	}
+}
+
+func TestDomainOf(t *testing.T) {
+	cases := map[string]string{
+		"user@Example.COM": "example.com",
+		"no-at-sign":       "",
+	}
+
+	for email, want := range cases {
+		if got := domainOf(email); got != want {
+			t.Errorf("domainOf(%q) = %q, want %q", email, got, want)
+		}
+	}
+}